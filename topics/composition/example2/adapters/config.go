@@ -0,0 +1,63 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package adapters
+
+import (
+	"fmt"
+
+	"github.com/x86nick/gotraining/topics/composition/example2/etl"
+	"gopkg.in/yaml.v3"
+)
+
+// Adapter names one registered adapter and the configuration to build it
+// with.
+type Adapter struct {
+	Type   string         `yaml:"type"`
+	Config map[string]any `yaml:"config"`
+}
+
+// PipelineConfig describes the Pullers and Storers a Pipeline should be
+// assembled from, as decoded from a YAML document.
+type PipelineConfig struct {
+	Pullers []Adapter `yaml:"pullers"`
+	Storers []Adapter `yaml:"storers"`
+}
+
+// LoadPipelineConfig decodes a YAML pipeline configuration from data.
+func LoadPipelineConfig(data []byte) (PipelineConfig, error) {
+	var cfg PipelineConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return PipelineConfig{}, fmt.Errorf("adapters: decode config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// BuildPullers constructs every configured Puller adapter, in order.
+func (c PipelineConfig) BuildPullers() ([]etl.Puller, error) {
+	pullers := make([]etl.Puller, len(c.Pullers))
+	for i, a := range c.Pullers {
+		puller, err := BuildPuller(a.Type, a.Config)
+		if err != nil {
+			return nil, err
+		}
+		pullers[i] = puller
+	}
+
+	return pullers, nil
+}
+
+// BuildStorers constructs every configured Storer adapter, in order.
+func (c PipelineConfig) BuildStorers() ([]etl.Storer, error) {
+	storers := make([]etl.Storer, len(c.Storers))
+	for i, a := range c.Storers {
+		storer, err := BuildStorer(a.Type, a.Config)
+		if err != nil {
+			return nil, err
+		}
+		storers[i] = storer
+	}
+
+	return storers, nil
+}
@@ -0,0 +1,105 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package mysql provides an etl.Puller that streams rows out of a MySQL
+// table.
+package mysql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/x86nick/gotraining/topics/composition/example2/adapters"
+	"github.com/x86nick/gotraining/topics/composition/example2/etl"
+)
+
+// Config configures a Puller.
+type Config struct {
+	// DSN is a database/sql data source name, e.g.
+	// "user:pass@tcp(127.0.0.1:3306)/db".
+	DSN string
+
+	// Query selects the rows to stream. It must select exactly one
+	// column, holding the line to copy.
+	Query string
+}
+
+// Puller streams rows from a MySQL query as an etl.Puller, one row per call
+// to Pull. A *sql.Rows cursor isn't safe for concurrent use, so Pull is
+// guarded by mu, making Puller safe to use with more than one pull worker.
+type Puller struct {
+	mu   sync.Mutex
+	db   *sql.DB
+	rows *sql.Rows
+}
+
+// New opens cfg.DSN and starts streaming cfg.Query.
+func New(cfg Config) (*Puller, error) {
+	db, err := sql.Open("mysql", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("mysql: open: %w", err)
+	}
+
+	rows, err := db.Query(cfg.Query)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("mysql: query: %w", err)
+	}
+
+	return &Puller{db: db, rows: rows}, nil
+}
+
+// Pull scans the next row into d, reporting etl.EOD once the result set is
+// exhausted. A failure to advance the cursor is only reported as temporary
+// when it looks like a dropped connection; a malformed query or similar
+// permanent failure is returned as-is so it can reach a dead-letter Storer
+// or abort the copy instead of being retried forever.
+func (p *Puller) Pull(d *etl.Data) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.rows.Next() {
+		err := p.rows.Err()
+		p.rows.Close()
+		p.db.Close()
+		if err != nil {
+			if isTemporary(err) {
+				return &etl.TemporaryError{Err: err}
+			}
+			return err
+		}
+
+		return etl.EOD
+	}
+
+	return p.rows.Scan(&d.Line)
+}
+
+// isTemporary reports whether err looks like a transient connection problem
+// that a retry may recover from, rather than a permanent failure such as a
+// bad query.
+func isTemporary(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func init() {
+	adapters.RegisterPuller("mysql", func(cfg map[string]any) (etl.Puller, error) {
+		dsn, _ := cfg["dsn"].(string)
+		query, _ := cfg["query"].(string)
+		if dsn == "" || query == "" {
+			return nil, fmt.Errorf("mysql: dsn and query are required")
+		}
+
+		return New(Config{DSN: dsn, Query: query})
+	})
+}
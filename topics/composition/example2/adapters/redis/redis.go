@@ -0,0 +1,79 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package redis provides an etl.Storer that XADDs every record to a Redis
+// stream.
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/x86nick/gotraining/topics/composition/example2/adapters"
+	"github.com/x86nick/gotraining/topics/composition/example2/etl"
+)
+
+// Config configures a Storer.
+type Config struct {
+	// Addr is the Redis server address, e.g. "127.0.0.1:6379".
+	Addr string
+
+	// Stream is the name of the stream records are added to.
+	Stream string
+}
+
+// Storer XADDs every Data it stores to a Redis stream, as an etl.Storer.
+type Storer struct {
+	client *redis.Client
+	stream string
+}
+
+// New returns a Storer that writes to cfg.Stream on the server at cfg.Addr.
+func New(cfg Config) *Storer {
+	return &Storer{
+		client: redis.NewClient(&redis.Options{Addr: cfg.Addr}),
+		stream: cfg.Stream,
+	}
+}
+
+// Store XADDs d to the configured stream. Only a failure to reach the
+// server is reported as temporary; a permanent failure such as bad
+// credentials or a wrong-type key is returned as-is so it can reach a
+// dead-letter Storer instead of being retried forever.
+func (s *Storer) Store(d etl.Data) error {
+	err := s.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: s.stream,
+		Values: map[string]any{"line": d.Line},
+	}).Err()
+	if err != nil {
+		if isTemporary(err) {
+			return &etl.TemporaryError{Err: err}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// isTemporary reports whether err looks like a transient connection problem
+// that a retry may recover from, rather than a permanent failure such as
+// bad credentials.
+func isTemporary(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func init() {
+	adapters.RegisterStorer("redis", func(cfg map[string]any) (etl.Storer, error) {
+		addr, _ := cfg["addr"].(string)
+		stream, _ := cfg["stream"].(string)
+		if addr == "" || stream == "" {
+			return nil, fmt.Errorf("redis: addr and stream are required")
+		}
+
+		return New(Config{Addr: addr, Stream: stream}), nil
+	})
+}
@@ -0,0 +1,63 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package adapters lets a Pipeline be assembled from named, externally
+// registered Puller and Storer implementations, such as the ones in
+// adapters/mysql, adapters/redis and adapters/s3, rather than from types
+// compiled directly into the program.
+package adapters
+
+import (
+	"fmt"
+
+	"github.com/x86nick/gotraining/topics/composition/example2/etl"
+)
+
+// PullerFactory builds a Puller from configuration decoded from a pipeline
+// config file.
+type PullerFactory func(cfg map[string]any) (etl.Puller, error)
+
+// StorerFactory builds a Storer from configuration decoded from a pipeline
+// config file.
+type StorerFactory func(cfg map[string]any) (etl.Storer, error)
+
+var (
+	pullerFactories = map[string]PullerFactory{}
+	storerFactories = map[string]StorerFactory{}
+)
+
+// RegisterPuller makes a named Puller adapter available to BuildPuller. It
+// is meant to be called from an adapter package's init function.
+func RegisterPuller(name string, factory PullerFactory) {
+	pullerFactories[name] = factory
+}
+
+// RegisterStorer makes a named Storer adapter available to BuildStorer. It
+// is meant to be called from an adapter package's init function.
+func RegisterStorer(name string, factory StorerFactory) {
+	storerFactories[name] = factory
+}
+
+// BuildPuller constructs the named Puller adapter from cfg. The adapter
+// package must have been imported, typically with a blank import, so its
+// init function has registered it.
+func BuildPuller(name string, cfg map[string]any) (etl.Puller, error) {
+	factory, ok := pullerFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("adapters: no puller registered for %q", name)
+	}
+
+	return factory(cfg)
+}
+
+// BuildStorer constructs the named Storer adapter from cfg. The adapter
+// package must have been imported, typically with a blank import, so its
+// init function has registered it.
+func BuildStorer(name string, cfg map[string]any) (etl.Storer, error) {
+	factory, ok := storerFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("adapters: no storer registered for %q", name)
+	}
+
+	return factory(cfg)
+}
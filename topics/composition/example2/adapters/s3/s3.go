@@ -0,0 +1,124 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package s3 provides an etl.Storer that writes every record as its own
+// object in an S3-compatible bucket.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	smithy "github.com/aws/smithy-go"
+	"github.com/x86nick/gotraining/topics/composition/example2/adapters"
+	"github.com/x86nick/gotraining/topics/composition/example2/etl"
+)
+
+// Config configures a Storer.
+type Config struct {
+	// Endpoint is the S3-compatible endpoint to talk to, e.g. a MinIO
+	// instance.
+	Endpoint string
+
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+
+	// Prefix is prepended to every generated object key.
+	Prefix string
+}
+
+// Storer writes every Data it stores as its own object in an S3-compatible
+// bucket, as an etl.Storer.
+type Storer struct {
+	client *awss3.Client
+	bucket string
+	prefix string
+	seq    uint64
+}
+
+// New returns a Storer that writes objects into cfg.Bucket.
+func New(cfg Config) *Storer {
+	client := awss3.New(awss3.Options{
+		Region:       cfg.Region,
+		BaseEndpoint: aws.String(cfg.Endpoint),
+		Credentials:  credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, ""),
+	})
+
+	return &Storer{client: client, bucket: cfg.Bucket, prefix: cfg.Prefix}
+}
+
+// Store writes d.Line as a new object, keyed by an incrementing sequence
+// number under the configured prefix. Only a failure that looks transient,
+// such as a dropped connection or a throttled request, is reported as
+// temporary; a permanent failure such as a missing bucket or bad
+// credentials is returned as-is so it can reach a dead-letter Storer
+// instead of being retried forever.
+func (s *Storer) Store(d etl.Data) error {
+	key := fmt.Sprintf("%s%d", s.prefix, atomic.AddUint64(&s.seq, 1))
+
+	_, err := s.client.PutObject(context.Background(), &awss3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader([]byte(d.Line)),
+	})
+	if err != nil {
+		if isTemporary(err) {
+			return &etl.TemporaryError{Err: err}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// isTemporary reports whether err looks like a transient failure, such as a
+// dropped connection or a throttled request, that a retry may recover
+// from.
+func isTemporary(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "RequestTimeout", "SlowDown", "ServiceUnavailable", "InternalError", "Throttling":
+			return true
+		}
+	}
+
+	return false
+}
+
+func init() {
+	adapters.RegisterStorer("s3", func(cfg map[string]any) (etl.Storer, error) {
+		get := func(k string) string {
+			v, _ := cfg[k].(string)
+			return v
+		}
+
+		bucket := get("bucket")
+		if bucket == "" {
+			return nil, fmt.Errorf("s3: bucket is required")
+		}
+
+		return New(Config{
+			Endpoint:  get("endpoint"),
+			Region:    get("region"),
+			Bucket:    bucket,
+			AccessKey: get("access_key"),
+			SecretKey: get("secret_key"),
+			Prefix:    get("prefix"),
+		}), nil
+	})
+}
@@ -0,0 +1,99 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package bufpool provides a reference-counted, size-classed pool of
+// slices, so a high-throughput copy loop can reuse a batch's backing array
+// instead of allocating a new one on every iteration.
+package bufpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// classes is the number of power-of-two size classes a Pool manages, from
+// 1<<0 up to 1<<(classes-1).
+const classes = 21
+
+// Pool is a size-classed pool of slices of T. Buffers are bucketed by the
+// smallest power-of-two capacity that fits a request, bounding the number
+// of distinct slice sizes the underlying sync.Pools have to manage.
+type Pool[T any] struct {
+	buckets [classes]sync.Pool
+}
+
+// New returns a Pool ready to Acquire buffers of T from.
+func New[T any]() *Pool[T] {
+	p := &Pool[T]{}
+	for class := range p.buckets {
+		size := 1 << uint(class)
+		p.buckets[class].New = func() any {
+			return make([]T, size)
+		}
+	}
+
+	return p
+}
+
+// classFor returns the index of the smallest size class whose capacity is
+// at least n.
+func classFor(n int) int {
+	class, size := 0, 1
+	for size < n {
+		size <<= 1
+		class++
+	}
+
+	return class
+}
+
+// =============================================================================
+
+// Buf is a reference-counted slice of T drawn from a Pool. The zero value is
+// not usable; obtain one with Pool.Acquire.
+type Buf[T any] struct {
+	Data []T
+
+	pool  *Pool[T]
+	class int
+	refs  int32
+}
+
+// Acquire returns a Buf whose Data has length n, drawn from the smallest
+// size class that fits. A request larger than the biggest size class falls
+// back to a plain allocation that bypasses the pool. The returned Buf starts
+// with one reference; call Release when done with it.
+func (p *Pool[T]) Acquire(n int) *Buf[T] {
+	class := classFor(n)
+	if class >= len(p.buckets) {
+		return &Buf[T]{Data: make([]T, n), class: -1, refs: 1}
+	}
+
+	full := p.buckets[class].Get().([]T)
+	return &Buf[T]{Data: full[:n], pool: p, class: class, refs: 1}
+}
+
+// Retain increments b's reference count. Call it before handing b to
+// another goroutine that will call Release independently, such as a Storer
+// that wants to keep working with a batch after the pipeline has moved on.
+func (b *Buf[T]) Retain() {
+	atomic.AddInt32(&b.refs, 1)
+}
+
+// Release decrements b's reference count, returning the backing slice to
+// its size class once the last reference is released.
+func (b *Buf[T]) Release() {
+	if atomic.AddInt32(&b.refs, -1) > 0 {
+		return
+	}
+	if b.class < 0 {
+		return
+	}
+
+	full := b.Data[:cap(b.Data)]
+	var zero T
+	for i := range full {
+		full[i] = zero
+	}
+	b.pool.buckets[b.class].Put(full)
+}
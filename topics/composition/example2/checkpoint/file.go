@@ -0,0 +1,79 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+// File is an etl.Checkpointer backed by a single local JSON file, so saved
+// offsets survive a process restart.
+type File struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile returns a File checkpointer that reads and writes path. path does
+// not need to exist yet.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Load returns the last offset saved for streamID, or a nil offset if none
+// has been saved yet.
+func (f *File) Load(streamID string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	offsets, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return offsets[streamID], nil
+}
+
+// Save persists offset as the latest position reached in streamID.
+func (f *File) Save(streamID string, offset []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	offsets, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	offsets[streamID] = offset
+
+	data, err := json.MarshalIndent(offsets, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f.path, data, 0o644)
+}
+
+// read loads the full set of saved offsets from disk, treating a missing
+// file as empty.
+func (f *File) read() (map[string][]byte, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string][]byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := map[string][]byte{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &offsets); err != nil {
+			return nil, err
+		}
+	}
+
+	return offsets, nil
+}
@@ -0,0 +1,42 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package checkpoint provides etl.Checkpointer implementations backed by
+// memory, a local JSON file, and Redis.
+package checkpoint
+
+import "sync"
+
+// Memory is an in-memory etl.Checkpointer. It does not survive a process
+// restart, so it is mainly useful for tests and for Storers that are
+// themselves process-local.
+type Memory struct {
+	mu      sync.Mutex
+	offsets map[string][]byte
+}
+
+// NewMemory returns an empty Memory checkpointer.
+func NewMemory() *Memory {
+	return &Memory{offsets: map[string][]byte{}}
+}
+
+// Load returns the last offset saved for streamID, or a nil offset if none
+// has been saved yet.
+func (m *Memory) Load(streamID string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.offsets[streamID], nil
+}
+
+// Save persists offset as the latest position reached in streamID.
+func (m *Memory) Save(streamID string, offset []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := make([]byte, len(offset))
+	copy(saved, offset)
+	m.offsets[streamID] = saved
+
+	return nil
+}
@@ -0,0 +1,43 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package checkpoint
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is an etl.Checkpointer backed by a Redis server, so saved offsets
+// are shared across every process copying the same stream.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis returns a Redis checkpointer that stores each stream's offset
+// under prefix+streamID.
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+// Load returns the last offset saved for streamID, or a nil offset if none
+// has been saved yet.
+func (r *Redis) Load(streamID string) ([]byte, error) {
+	offset, err := r.client.Get(context.Background(), r.key(streamID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	return offset, err
+}
+
+// Save persists offset as the latest position reached in streamID.
+func (r *Redis) Save(streamID string, offset []byte) error {
+	return r.client.Set(context.Background(), r.key(streamID), offset, 0).Err()
+}
+
+func (r *Redis) key(streamID string) string {
+	return r.prefix + streamID
+}
@@ -0,0 +1,34 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package etl
+
+// Checkpointer can persist and recall a stream's last processed offset, so
+// a Copy interrupted partway through can resume instead of starting over.
+//
+// Copy only saves an offset once a batch has been fully stored, so an
+// interruption mid-batch is recovered by re-pulling and re-storing that
+// whole batch on the next run: Checkpointer gives Copy at-least-once, not
+// exactly-once, delivery. A Storer that cannot tolerate storing the same
+// Data twice should make Store idempotent, e.g. by keying records so a
+// repeat store overwrites rather than duplicates.
+type Checkpointer interface {
+	// Load returns the last offset saved for streamID, or a nil offset if
+	// none has been saved yet.
+	Load(streamID string) (offset []byte, err error)
+
+	// Save persists offset as the latest position reached in streamID.
+	Save(streamID string, offset []byte) error
+}
+
+// Resumable is implemented by a Puller that can report its current
+// position and seek back to a previously reported one.
+type Resumable interface {
+	Puller
+
+	// Seek resumes pulling from offset, as previously returned by Offset.
+	Seek(offset []byte) error
+
+	// Offset reports the position to resume from on a future Seek.
+	Offset() []byte
+}
@@ -0,0 +1,123 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package etl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/x86nick/gotraining/topics/composition/example2/checkpoint"
+)
+
+// countingPuller pulls a fixed number of records and supports Resumable by
+// tracking how many it has pulled so far.
+type countingPuller struct {
+	pos int
+	max int
+}
+
+func (p *countingPuller) Pull(d *Data) error {
+	if p.pos >= p.max {
+		return EOD
+	}
+
+	d.Line = fmt.Sprintf("record-%d", p.pos)
+	p.pos++
+
+	return nil
+}
+
+func (p *countingPuller) Seek(offset []byte) error {
+	pos, err := strconv.Atoi(string(offset))
+	if err != nil {
+		return err
+	}
+
+	p.pos = pos
+	return nil
+}
+
+func (p *countingPuller) Offset() []byte {
+	return []byte(strconv.Itoa(p.pos))
+}
+
+// crashAfterStorer stores every record it is given up to a limit, then
+// fails every call after that, simulating a process that dies mid-batch.
+type crashAfterStorer struct {
+	stored []Data
+	limit  int
+}
+
+func (s *crashAfterStorer) Store(d Data) error {
+	if len(s.stored) >= s.limit {
+		return errors.New("simulated crash")
+	}
+
+	s.stored = append(s.stored, d)
+	return nil
+}
+
+// recordingStorer records every Data it is given, in order.
+type recordingStorer struct {
+	stored []Data
+}
+
+func (s *recordingStorer) Store(d Data) error {
+	s.stored = append(s.stored, d)
+	return nil
+}
+
+func TestCopyResumesAfterMidBatchCrash(t *testing.T) {
+	// total is not a multiple of the batch size below, so the final batch
+	// pulls fewer records than it asks for before the Puller reports EOD,
+	// exercising Copy's handling of a batch that spans the end of the
+	// stream as well as a crash mid-stream.
+	const total = 8
+	cp := checkpoint.NewMemory()
+
+	puller := &countingPuller{max: total}
+	crasher := &crashAfterStorer{limit: 7}
+
+	io := IO{Checkpointer: cp, StreamID: "test-stream"}
+	sys := &System{Puller: puller, Storer: crasher}
+
+	if err := io.Copy(sys, 3); err == nil {
+		t.Fatal("expected the simulated crash to stop the copy")
+	}
+
+	// The batch in flight when the crash happened stored one record before
+	// failing on the next.
+	if got := len(crasher.stored); got != 7 {
+		t.Fatalf("stored %d records before crashing, want 7", got)
+	}
+
+	// Resume with a fresh Puller (as a restarted process would have) and a
+	// Storer that keeps working, and make sure nothing pulled before the
+	// crash is lost.
+	resumedPuller := &countingPuller{max: total}
+	recorder := &recordingStorer{}
+	resumedIO := IO{Checkpointer: cp, StreamID: "test-stream"}
+	resumedSys := &System{Puller: resumedPuller, Storer: recorder}
+
+	if err := resumedIO.Copy(resumedSys, 3); err != EOD {
+		t.Fatalf("resumed copy ended with %v, want EOD", err)
+	}
+
+	seen := map[string]bool{}
+	for _, d := range crasher.stored {
+		seen[d.Line] = true
+	}
+	for _, d := range recorder.stored {
+		seen[d.Line] = true
+	}
+
+	for i := 0; i < total; i++ {
+		line := fmt.Sprintf("record-%d", i)
+		if !seen[line] {
+			t.Errorf("%s was never stored across the crash and resume", line)
+		}
+	}
+}
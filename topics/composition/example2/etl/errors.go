@@ -0,0 +1,44 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package etl
+
+// temporary is satisfied by errors that describe a transient failure that
+// may succeed if the operation is retried, following the same convention as
+// net.Error.
+type temporary interface {
+	Temporary() bool
+}
+
+// IsTemporary reports whether err describes a transient failure rather than
+// a terminal one. EOD is never temporary: it signals the end of the stream,
+// not a failure.
+func IsTemporary(err error) bool {
+	if err == nil || err == EOD {
+		return false
+	}
+
+	te, ok := err.(temporary)
+	return ok && te.Temporary()
+}
+
+// TemporaryError marks Err as transient, letting middleware such as retry
+// and dead-letter decide whether to act on it.
+type TemporaryError struct {
+	Err error
+}
+
+// Error returns the wrapped error's message.
+func (e *TemporaryError) Error() string {
+	return e.Err.Error()
+}
+
+// Temporary reports that this error is transient.
+func (e *TemporaryError) Temporary() bool {
+	return true
+}
+
+// Unwrap returns the wrapped error for use with errors.Is and errors.As.
+func (e *TemporaryError) Unwrap() error {
+	return e.Err
+}
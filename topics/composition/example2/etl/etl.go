@@ -0,0 +1,212 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package etl holds the decoupled pull/store primitives used by the
+// composition/example2 sample. Pulling it out of main lets other packages,
+// such as metrics, wrap a Puller or Storer without depending on the example's
+// entry point.
+package etl
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/x86nick/gotraining/topics/composition/example2/bufpool"
+)
+
+// pool backs every batch IO pulls and stores, so repeated copies reuse the
+// same backing arrays instead of allocating a new one per batch.
+var pool = bufpool.New[Data]()
+
+// =============================================================================
+
+// EOD represents the end of the data stream.
+var EOD = errors.New("EOD")
+
+// Data is the structure of the data we are copying.
+type Data struct {
+	Line string
+}
+
+// =============================================================================
+
+// Puller declares behavior for pulling data. A Puller given to a Pipeline
+// with more than one pull worker must support Pull being called
+// concurrently from multiple goroutines.
+type Puller interface {
+	Pull(d *Data) error
+}
+
+// Storer declares behavior for storing data.
+type Storer interface {
+	Store(d Data) error
+}
+
+// =============================================================================
+
+// Xenia is a system we need to pull data from. pos counts how many records
+// have been pulled, letting Xenia support Resumable. A Pipeline may run
+// several pull workers against the same Puller, so pos is an atomic.Int64
+// rather than a plain int, making Xenia safe to Pull concurrently.
+type Xenia struct {
+	pos atomic.Int64
+}
+
+// Pull knows how to pull data out of Xenia.
+func (x *Xenia) Pull(d *Data) error {
+	switch rand.Intn(10) {
+	case 1, 9:
+		return EOD
+	case 5:
+		return &TemporaryError{Err: errors.New("Error reading data from Xenia")}
+	}
+
+	d.Line = "Data"
+	x.pos.Add(1)
+	fmt.Println("In:", d.Line)
+
+	return nil
+}
+
+// Seek implements Resumable, resuming from a previously checkpointed
+// position.
+func (x *Xenia) Seek(offset []byte) error {
+	pos, err := strconv.Atoi(string(offset))
+	if err != nil {
+		return fmt.Errorf("xenia: seek: %w", err)
+	}
+
+	x.pos.Store(int64(pos))
+	return nil
+}
+
+// Offset implements Resumable, reporting how many records have been pulled
+// so far.
+func (x *Xenia) Offset() []byte {
+	return []byte(strconv.FormatInt(x.pos.Load(), 10))
+}
+
+// Pillar is a system we need to store data into.
+type Pillar struct{}
+
+// Store knows how to store data into Pillar.
+func (Pillar) Store(d Data) error {
+	fmt.Println("Out:", d.Line)
+	return nil
+}
+
+// =============================================================================
+
+// System wraps a Puller and a Storer together into a single pipeline of one
+// source and one sink.
+type System struct {
+	Puller
+	Storer
+}
+
+// =============================================================================
+
+// BatchObserver can be implemented to observe the size of each batch IO.Copy
+// pulls and stores, such as for metrics.
+type BatchObserver interface {
+	ObserveBatch(n int)
+}
+
+// IO provides support to copy bulk data. Observer, if set, is notified with
+// the size of every batch after it is stored. Checkpointer, if set, is used
+// to resume a System's Puller from where a previous Copy left off, keyed by
+// StreamID, when that Puller implements Resumable.
+type IO struct {
+	Observer     BatchObserver
+	Checkpointer Checkpointer
+	StreamID     string
+}
+
+// pull knows how to pull bulks of data from any Puller into buf, returning
+// how many of buf's slots it filled before stopping. On a non-nil error,
+// including EOD, the first n slots still hold records that were pulled
+// successfully and must not be discarded.
+func (IO) pull(p Puller, buf *bufpool.Buf[Data]) (int, error) {
+	for i := range buf.Data {
+		if err := p.Pull(&buf.Data[i]); err != nil {
+			return i, err
+		}
+	}
+
+	return len(buf.Data), nil
+}
+
+// store knows how to store the bulk of data in buf into any Storer. It
+// stops at the first record a Storer fails to store; a Storer that wants to
+// keep a bad record from aborting the whole copy should recover from it
+// itself, such as by routing it to a dead-letter Storer.
+func (IO) store(s Storer, buf *bufpool.Buf[Data]) error {
+	for _, d := range buf.Data {
+		if err := s.Store(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Copy knows how to pull and store data from the System. If io.Checkpointer
+// is set and sys.Puller implements Resumable, Copy seeks it to the last
+// offset saved under io.StreamID before its first pull, and saves its new
+// offset after every batch is stored.
+func (io IO) Copy(sys *System, batch int) error {
+	resumable, resume := sys.Puller.(Resumable)
+	if resume && io.Checkpointer != nil {
+		offset, err := io.Checkpointer.Load(io.StreamID)
+		if err != nil {
+			return err
+		}
+		if offset != nil {
+			if err := resumable.Seek(offset); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		buf := pool.Acquire(batch)
+
+		n, pullErr := io.pull(sys.Puller, buf)
+		if pullErr != nil && pullErr != EOD {
+			buf.Release()
+			return pullErr
+		}
+
+		// Even when the Puller hit EOD partway through this batch, the
+		// records it already pulled are real and must be stored and
+		// checkpointed before Copy reports the stream as done.
+		if n > 0 {
+			buf.Data = buf.Data[:n]
+
+			if err := io.store(sys.Storer, buf); err != nil {
+				buf.Release()
+				return err
+			}
+
+			if io.Observer != nil {
+				io.Observer.ObserveBatch(len(buf.Data))
+			}
+
+			if resume && io.Checkpointer != nil {
+				if err := io.Checkpointer.Save(io.StreamID, resumable.Offset()); err != nil {
+					buf.Release()
+					return err
+				}
+			}
+		}
+
+		buf.Release()
+
+		if pullErr == EOD {
+			return EOD
+		}
+	}
+}
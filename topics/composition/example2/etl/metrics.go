@@ -0,0 +1,21 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package etl
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ServeMetrics starts an HTTP server on addr exposing every Prometheus
+// metric registered in the process, such as the ones the metrics package's
+// Puller and Storer decorators record, at /metrics. It blocks until the
+// server stops or returns an error.
+func (IO) ServeMetrics(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return http.ListenAndServe(addr, mux)
+}
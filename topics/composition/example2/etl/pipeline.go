@@ -0,0 +1,230 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package etl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/x86nick/gotraining/topics/composition/example2/bufpool"
+)
+
+// =============================================================================
+
+// DropPolicy determines what a Pipeline does when its in-flight buffer of
+// batches is full.
+type DropPolicy int
+
+// The set of supported back-pressure policies.
+const (
+	// Block makes pullers wait for room in the buffer before pulling more
+	// data. This is the default and never loses data.
+	Block DropPolicy = iota
+
+	// DropOldest discards the oldest buffered batch to make room for the
+	// newest one, trading data loss for pullers that never stall.
+	DropOldest
+)
+
+// PipelineConfig configures the concurrency and back-pressure behavior of a
+// Pipeline.
+type PipelineConfig struct {
+	// Batch is the number of Data values pulled into each batch, one per
+	// call to Pull.
+	Batch int
+
+	// PullWorkers is the number of goroutines started for each Puller. A
+	// value greater than 1 means every Puller passed to Copy must support
+	// concurrent calls to Pull, since each of its workers shares the same
+	// Puller instance.
+	PullWorkers int
+
+	// StoreWorkers is the number of goroutines started for each Storer.
+	StoreWorkers int
+
+	// BufferSize bounds the number of in-flight batches between the pull
+	// and store stages, providing back-pressure.
+	BufferSize int
+
+	// Drop controls what happens when the buffer is full.
+	Drop DropPolicy
+}
+
+// defaults fills in sane values for any field left at its zero value.
+func (cfg PipelineConfig) defaults() PipelineConfig {
+	if cfg.Batch <= 0 {
+		cfg.Batch = 3
+	}
+	if cfg.PullWorkers <= 0 {
+		cfg.PullWorkers = 1
+	}
+	if cfg.StoreWorkers <= 0 {
+		cfg.StoreWorkers = 1
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = cfg.PullWorkers * 2
+	}
+
+	return cfg
+}
+
+// =============================================================================
+
+// Pipeline fans data out of multiple Pullers and fans it in to multiple
+// Storers, running each stage as a pool of goroutines connected by a
+// buffered channel of batches.
+type Pipeline struct {
+	io  IO
+	cfg PipelineConfig
+}
+
+// NewPipeline returns a Pipeline ready to copy data with the given
+// configuration.
+func NewPipeline(cfg PipelineConfig) *Pipeline {
+	return &Pipeline{cfg: cfg.defaults()}
+}
+
+// Copy pulls from every Puller and stores into every Storer concurrently. It
+// stops when ctx is canceled, every Puller reports EOD, or any Puller or
+// Storer returns a non-EOD error — Copy itself has no notion of a
+// recoverable failure, so a Puller or Storer backed by a real, flaky
+// backend (such as the mysql/redis/s3 adapters) should be wrapped with the
+// middleware package's retry, breaker and dead-letter decorators before
+// being handed to Copy, the same way the package's own demo wraps them.
+func (p *Pipeline) Copy(ctx context.Context, pullers []Puller, storers []Storer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan *bufpool.Buf[Data], p.cfg.BufferSize)
+	errs := make(chan error, len(pullers)*p.cfg.PullWorkers+len(storers)*p.cfg.StoreWorkers)
+
+	var pullWG sync.WaitGroup
+	for _, puller := range pullers {
+		for w := 0; w < p.cfg.PullWorkers; w++ {
+			pullWG.Add(1)
+			go func(puller Puller) {
+				defer pullWG.Done()
+				p.runPuller(ctx, cancel, puller, batches, errs)
+			}(puller)
+		}
+	}
+
+	go func() {
+		pullWG.Wait()
+		close(batches)
+	}()
+
+	var storeWG sync.WaitGroup
+	for _, storer := range storers {
+		for w := 0; w < p.cfg.StoreWorkers; w++ {
+			storeWG.Add(1)
+			go func(storer Storer) {
+				defer storeWG.Done()
+				p.runStorer(ctx, cancel, storer, batches, errs)
+			}(storer)
+		}
+	}
+	storeWG.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// runPuller repeatedly pulls a batch from puller into a buffer drawn from
+// pool and hands it to out until ctx is canceled or puller reports EOD or an
+// error.
+func (p *Pipeline) runPuller(ctx context.Context, cancel context.CancelFunc, puller Puller, out chan *bufpool.Buf[Data], errs chan<- error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		buf := pool.Acquire(p.cfg.Batch)
+		n, err := p.io.pull(puller, buf)
+		if err != nil {
+			// Even on EOD, whatever this batch already pulled is real and
+			// must still reach a Storer.
+			if n > 0 {
+				buf.Data = buf.Data[:n]
+				p.send(ctx, out, buf)
+			} else {
+				buf.Release()
+			}
+			if err != EOD {
+				errs <- err
+				cancel()
+			}
+			return
+		}
+
+		p.send(ctx, out, buf)
+	}
+}
+
+// send delivers buf to out according to the configured DropPolicy, releasing
+// it if it is dropped or if ctx is canceled before it can be delivered. out
+// must be bidirectional, not send-only, since DropOldest receives from it to
+// make room.
+func (p *Pipeline) send(ctx context.Context, out chan *bufpool.Buf[Data], buf *bufpool.Buf[Data]) {
+	if p.cfg.Drop == DropOldest {
+		// Every attempt here is non-blocking: DropOldest exists so a
+		// puller never stalls waiting for room, even if another goroutine
+		// races it to fill the space this frees up.
+		select {
+		case out <- buf:
+			return
+		default:
+		}
+
+		select {
+		case old := <-out:
+			old.Release()
+		default:
+		}
+
+		select {
+		case out <- buf:
+		default:
+			buf.Release()
+		}
+
+		return
+	}
+
+	select {
+	case out <- buf:
+	case <-ctx.Done():
+		buf.Release()
+	}
+}
+
+// runStorer stores every batch read from in, releasing each one back to the
+// pool once it has been stored, until in is closed, ctx is canceled, or
+// storer fails to store a batch.
+func (p *Pipeline) runStorer(ctx context.Context, cancel context.CancelFunc, storer Storer, in <-chan *bufpool.Buf[Data], errs chan<- error) {
+	for {
+		select {
+		case buf, ok := <-in:
+			if !ok {
+				return
+			}
+			err := p.io.store(storer, buf)
+			buf.Release()
+			if err != nil {
+				errs <- err
+				cancel()
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
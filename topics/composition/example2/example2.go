@@ -5,121 +5,136 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"math/rand"
 	"time"
+
+	"github.com/x86nick/gotraining/topics/composition/example2/adapters"
+	_ "github.com/x86nick/gotraining/topics/composition/example2/adapters/mysql"
+	_ "github.com/x86nick/gotraining/topics/composition/example2/adapters/redis"
+	_ "github.com/x86nick/gotraining/topics/composition/example2/adapters/s3"
+	"github.com/x86nick/gotraining/topics/composition/example2/checkpoint"
+	"github.com/x86nick/gotraining/topics/composition/example2/etl"
+	"github.com/x86nick/gotraining/topics/composition/example2/metrics"
+	"github.com/x86nick/gotraining/topics/composition/example2/middleware"
 )
 
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
-// =============================================================================
-
-// EOD represents the end of the data stream.
-var EOD = errors.New("EOD")
-
-// Data is the structure of the data we are copying.
-type Data struct {
-	Line string
-}
-
-// =============================================================================
-
-// Puller declares behavior for pulling data.
-type Puller interface {
-	Pull(d *Data) error
-}
-
-// Storer declares behavior for storing data.
-type Storer interface {
-	Store(d Data)
-}
-
-// =============================================================================
-
-// Xenia is a system we need to pull data from.
-type Xenia struct{}
+func main() {
 
-// Pull knows how to pull data out of Xenia.
-func (Xenia) Pull(d *Data) error {
-	switch rand.Intn(10) {
-	case 1, 9:
-		return EOD
-	case 5:
-		return errors.New("Error reading data from Xenia")
+	// Initialize the system for use, checkpointing Xenia's position to a
+	// local file so a restart resumes instead of starting over.
+	sys := etl.System{
+		Puller: &etl.Xenia{},
+		Storer: etl.Pillar{},
 	}
 
-	d.Line = "Data"
-	fmt.Println("In:", d.Line)
-
-	return nil
-}
-
-// Pillar is a system we need to store data into.
-type Pillar struct{}
-
-// Store knows how to store data into Pillar.
-func (Pillar) Store(d Data) {
-	fmt.Println("Out:", d.Line)
-}
-
-// =============================================================================
-
-// System wraps Xenia and Pillar together into a single system.
-type System struct {
-	Xenia
-	Pillar
-}
-
-// =============================================================================
-
-// IO provides support to copy bulk data.
-type IO struct{}
+	io := etl.IO{
+		Observer:     metrics.BatchObserver{},
+		Checkpointer: checkpoint.NewFile("xenia.checkpoint.json"),
+		StreamID:     "xenia",
+	}
+	if err := io.Copy(&sys, 3); err != nil && err != etl.EOD {
+		fmt.Println(err)
+	}
 
-// pull knows how to pull bulks of data from any Puller.
-func (IO) pull(p Puller, data []Data) error {
-	for i := range data {
-		if err := p.Pull(&data[i]); err != nil {
-			return err
+	// Copy the same way, but from many Xenias into many Pillars
+	// concurrently, instrumenting every puller and storer and exposing
+	// the result at :9090/metrics.
+	go func() {
+		if err := (etl.IO{}).ServeMetrics(":9090"); err != nil {
+			fmt.Println(err)
 		}
+	}()
+
+	pipeline := etl.NewPipeline(etl.PipelineConfig{
+		Batch:        3,
+		PullWorkers:  2,
+		StoreWorkers: 2,
+		BufferSize:   4,
+		Drop:         etl.Block,
+	})
+
+	// A Xenia wrapped so a temporary read error is retried a few times
+	// before the circuit breaker can trip, and a Pillar wrapped so a
+	// terminal store error is diverted to deadLetter instead of aborting
+	// the copy.
+	deadLetter := etl.Pillar{}
+	newPuller := func() etl.Puller {
+		p := middleware.RetryPuller(&etl.Xenia{}, middleware.RetryConfig{})
+		p = middleware.BreakerPuller(p, middleware.BreakerConfig{})
+		return metrics.Puller(p)
+	}
+	newStorer := func() etl.Storer {
+		s := middleware.DeadLetterStorer(etl.Pillar{}, deadLetter)
+		s = middleware.RetryStorer(s, middleware.RetryConfig{})
+		return metrics.Storer(s)
 	}
 
-	return nil
-}
+	pullers := []etl.Puller{newPuller(), newPuller()}
+	storers := []etl.Storer{newStorer(), newStorer()}
 
-// store knows how to store bulks of data from any Storer.
-func (IO) store(s Storer, data []Data) {
-	for _, d := range data {
-		s.Store(d)
+	if err := pipeline.Copy(context.Background(), pullers, storers); err != nil && err != etl.EOD {
+		fmt.Println(err)
 	}
-}
 
-// Copy knows how to pull and store data from the System.
-func (io IO) Copy(sys *System, batch int) error {
-	for {
-		data := make([]Data, batch)
-		if err := io.pull(&sys.Xenia, data); err != nil {
-			return err
-		}
-
-		io.store(&sys.Pillar, data)
+	// A Pipeline can also be assembled entirely from configuration, wiring
+	// in real backends instead of the in-memory Xenia/Pillar stand-ins.
+	const config = `
+pullers:
+  - type: mysql
+    config:
+      dsn: user:pass@tcp(127.0.0.1:3306)/db
+      query: SELECT line FROM records
+storers:
+  - type: redis
+    config:
+      addr: 127.0.0.1:6379
+      stream: records
+  - type: s3
+    config:
+      endpoint: http://127.0.0.1:9000
+      region: us-east-1
+      bucket: records
+      access_key: minioadmin
+      secret_key: minioadmin
+`
+
+	cfg, err := adapters.LoadPipelineConfig([]byte(config))
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
-}
 
-// =============================================================================
+	cfgPullers, err := cfg.BuildPullers()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
-func main() {
+	cfgStorers, err := cfg.BuildStorers()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
-	// Initialize the system for use.
-	sys := System{
-		Xenia:  Xenia{},
-		Pillar: Pillar{},
+	// Config-built adapters talk to real backends, so a transient failure
+	// must not be allowed to abort the whole copy the way it would for the
+	// in-memory Xenia/Pillar stand-ins.
+	for i, p := range cfgPullers {
+		p = middleware.RetryPuller(p, middleware.RetryConfig{})
+		cfgPullers[i] = middleware.BreakerPuller(p, middleware.BreakerConfig{})
+	}
+	for i, s := range cfgStorers {
+		s = middleware.DeadLetterStorer(s, deadLetter)
+		cfgStorers[i] = middleware.RetryStorer(s, middleware.RetryConfig{})
 	}
 
-	var io IO
-	if err := io.Copy(&sys, 3); err != nil && err != EOD {
+	if err := pipeline.Copy(context.Background(), cfgPullers, cfgStorers); err != nil && err != etl.EOD {
 		fmt.Println(err)
 	}
 }
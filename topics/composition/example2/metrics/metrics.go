@@ -0,0 +1,126 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package metrics provides Prometheus instrumentation for the etl.Puller and
+// etl.Storer interfaces, without requiring callers to change any code
+// written against those interfaces.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/x86nick/gotraining/topics/composition/example2/etl"
+)
+
+// =============================================================================
+
+var (
+	// pullTotal counts every call to Pull, labeled by outcome.
+	pullTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pull_total",
+		Help: "Total number of Pull calls.",
+	}, []string{"outcome"})
+
+	// pullErrorsTotal counts Pull calls that returned a non-EOD error.
+	pullErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "pull_errors_total",
+		Help: "Total number of Pull calls that returned an error.",
+	})
+
+	// pullDurationSeconds observes how long each Pull call takes.
+	pullDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pull_duration_seconds",
+		Help:    "Duration of Pull calls in seconds.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// storeTotal counts every call to Store.
+	storeTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "store_total",
+		Help: "Total number of Store calls.",
+	})
+
+	// batchSize observes the size of each batch passed through IO.Copy.
+	batchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "batch_size",
+		Help:    "Number of records per batch.",
+		Buckets: prometheus.LinearBuckets(1, 2, 10),
+	})
+)
+
+func init() {
+	prometheus.MustRegister(pullTotal, pullErrorsTotal, pullDurationSeconds, storeTotal, batchSize)
+}
+
+// =============================================================================
+
+// puller wraps an etl.Puller and records metrics around every call to Pull.
+type puller struct {
+	inner etl.Puller
+}
+
+// Puller decorates inner so every Pull call is instrumented.
+func Puller(inner etl.Puller) etl.Puller {
+	return &puller{inner: inner}
+}
+
+// Pull calls through to the wrapped Puller, recording pull_total,
+// pull_errors_total and pull_duration_seconds. EOD gets its own outcome
+// label rather than "error", since it signals a normal end of stream, not
+// a failure.
+func (p *puller) Pull(d *etl.Data) error {
+	start := time.Now()
+	err := p.inner.Pull(d)
+	pullDurationSeconds.Observe(time.Since(start).Seconds())
+
+	switch err {
+	case nil:
+		pullTotal.WithLabelValues("ok").Inc()
+	case etl.EOD:
+		pullTotal.WithLabelValues("eod").Inc()
+	default:
+		pullTotal.WithLabelValues("error").Inc()
+		pullErrorsTotal.Inc()
+	}
+
+	return err
+}
+
+// =============================================================================
+
+// storer wraps an etl.Storer and records metrics around every call to
+// Store.
+type storer struct {
+	inner etl.Storer
+}
+
+// Storer decorates inner so every Store call is instrumented.
+func Storer(inner etl.Storer) etl.Storer {
+	return &storer{inner: inner}
+}
+
+// Store calls through to the wrapped Storer, recording store_total.
+func (s *storer) Store(d etl.Data) error {
+	storeTotal.Inc()
+	return s.inner.Store(d)
+}
+
+// =============================================================================
+
+// BatchObserver implements etl.BatchObserver, recording the size of every
+// batch an instrumented IO.Copy processes.
+type BatchObserver struct{}
+
+// ObserveBatch records n in the batch_size histogram.
+func (BatchObserver) ObserveBatch(n int) {
+	batchSize.Observe(float64(n))
+}
+
+// Handler returns the http.Handler that exposes the registered metrics in
+// the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
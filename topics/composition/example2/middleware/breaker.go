@@ -0,0 +1,214 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package middleware
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/x86nick/gotraining/topics/composition/example2/etl"
+)
+
+// =============================================================================
+
+// ErrBreakerOpen is returned by a tripped circuit breaker instead of calling
+// through to the wrapped Puller or Storer. It is temporary: once the
+// breaker's cooldown elapses it moves to half-open and starts letting calls
+// through again.
+var ErrBreakerOpen error = &etl.TemporaryError{Err: errors.New("circuit breaker open")}
+
+// breakerState is the state of a circuit breaker.
+type breakerState int
+
+// The states a breaker moves through.
+const (
+	closed breakerState = iota
+	open
+	halfOpen
+)
+
+// BreakerConfig configures a circuit breaker's rolling error-rate window and
+// cooldown.
+type BreakerConfig struct {
+	// Window is how far back calls are counted when computing the error
+	// rate.
+	Window time.Duration
+
+	// MinSamples is the minimum number of calls in Window before the
+	// breaker will consider tripping.
+	MinSamples int
+
+	// ErrorThreshold is the fraction, in (0,1], of calls in Window that
+	// must fail to trip the breaker open.
+	ErrorThreshold float64
+
+	// Cooldown is how long the breaker stays open before letting a single
+	// half-open probe call through.
+	Cooldown time.Duration
+}
+
+// defaults fills in sane values for any field left at its zero value.
+func (cfg BreakerConfig) defaults() BreakerConfig {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 5
+	}
+	if cfg.ErrorThreshold <= 0 {
+		cfg.ErrorThreshold = 0.5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 5 * time.Second
+	}
+
+	return cfg
+}
+
+// =============================================================================
+
+// outcome records whether a single call succeeded, and when.
+type outcome struct {
+	at time.Time
+	ok bool
+}
+
+// breaker implements a closed -> open -> half-open circuit breaker over a
+// rolling error-rate window.
+type breaker struct {
+	cfg    BreakerConfig
+	mu     sync.Mutex
+	state  breakerState
+	opened time.Time
+	calls  []outcome
+}
+
+// newBreaker returns a breaker in the closed state.
+func newBreaker(cfg BreakerConfig) *breaker {
+	return &breaker{cfg: cfg.defaults()}
+}
+
+// allow reports whether a call should be let through, moving an open
+// breaker to half-open once the cooldown has elapsed.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case open:
+		if time.Since(b.opened) < b.cfg.Cooldown {
+			return false
+		}
+		b.state = halfOpen
+		return true
+
+	case halfOpen:
+		// Only one probe call is allowed through at a time.
+		return false
+
+	default:
+		return true
+	}
+}
+
+// record registers the outcome of a call, trimming the rolling window and
+// tripping or resetting the breaker as appropriate.
+func (b *breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.calls = append(b.calls, outcome{at: now, ok: ok})
+
+	cutoff := now.Add(-b.cfg.Window)
+	i := 0
+	for ; i < len(b.calls); i++ {
+		if b.calls[i].at.After(cutoff) {
+			break
+		}
+	}
+	b.calls = b.calls[i:]
+
+	if b.state == halfOpen {
+		if ok {
+			b.state = closed
+			b.calls = nil
+		} else {
+			b.state = open
+			b.opened = now
+		}
+		return
+	}
+
+	if len(b.calls) < b.cfg.MinSamples {
+		return
+	}
+
+	var failures int
+	for _, c := range b.calls {
+		if !c.ok {
+			failures++
+		}
+	}
+
+	if float64(failures)/float64(len(b.calls)) >= b.cfg.ErrorThreshold {
+		b.state = open
+		b.opened = now
+	}
+}
+
+// =============================================================================
+
+// breakerPuller wraps a Puller with a circuit breaker.
+type breakerPuller struct {
+	inner etl.Puller
+	b     *breaker
+}
+
+// BreakerPuller decorates inner with a circuit breaker that trips open once
+// cfg.ErrorThreshold of calls in cfg.Window fail, short-circuiting further
+// calls with ErrBreakerOpen until cfg.Cooldown elapses.
+func BreakerPuller(inner etl.Puller, cfg BreakerConfig) etl.Puller {
+	return &breakerPuller{inner: inner, b: newBreaker(cfg)}
+}
+
+// Pull calls through to the wrapped Puller unless the breaker is open.
+func (p *breakerPuller) Pull(d *etl.Data) error {
+	if !p.b.allow() {
+		return ErrBreakerOpen
+	}
+
+	err := p.inner.Pull(d)
+	p.b.record(err == nil || err == etl.EOD)
+
+	return err
+}
+
+// =============================================================================
+
+// breakerStorer wraps a Storer with a circuit breaker.
+type breakerStorer struct {
+	inner etl.Storer
+	b     *breaker
+}
+
+// BreakerStorer decorates inner with a circuit breaker that trips open once
+// cfg.ErrorThreshold of calls in cfg.Window fail, short-circuiting further
+// calls with ErrBreakerOpen until cfg.Cooldown elapses.
+func BreakerStorer(inner etl.Storer, cfg BreakerConfig) etl.Storer {
+	return &breakerStorer{inner: inner, b: newBreaker(cfg)}
+}
+
+// Store calls through to the wrapped Storer unless the breaker is open.
+func (s *breakerStorer) Store(d etl.Data) error {
+	if !s.b.allow() {
+		return ErrBreakerOpen
+	}
+
+	err := s.inner.Store(d)
+	s.b.record(err == nil)
+
+	return err
+}
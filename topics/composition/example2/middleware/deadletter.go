@@ -0,0 +1,33 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package middleware
+
+import "github.com/x86nick/gotraining/topics/composition/example2/etl"
+
+// deadLetterStorer wraps a Storer, diverting any Data it fails to store
+// with a terminal error to a dead-letter Storer.
+type deadLetterStorer struct {
+	inner etl.Storer
+	dead  etl.Storer
+}
+
+// DeadLetterStorer decorates inner so a terminal (non-temporary) Store
+// error is recovered from by handing d to dead instead of propagating the
+// error, which would otherwise abort the whole copy. A temporary error is
+// passed through unchanged so retry middleware further up the chain still
+// gets a chance to retry it.
+func DeadLetterStorer(inner, dead etl.Storer) etl.Storer {
+	return &deadLetterStorer{inner: inner, dead: dead}
+}
+
+// Store calls through to the wrapped Storer, diverting d to the dead-letter
+// Storer on a terminal error.
+func (s *deadLetterStorer) Store(d etl.Data) error {
+	err := s.inner.Store(d)
+	if err == nil || etl.IsTemporary(err) {
+		return err
+	}
+
+	return s.dead.Store(d)
+}
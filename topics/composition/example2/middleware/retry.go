@@ -0,0 +1,119 @@
+// All material is licensed under the Apache License Version 2.0, January 2004
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package middleware provides Puller and Storer decorators for retrying
+// transient failures, tripping a circuit breaker under sustained failures,
+// and diverting unrecoverable records to a dead-letter Storer.
+package middleware
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/x86nick/gotraining/topics/composition/example2/etl"
+)
+
+// =============================================================================
+
+// RetryConfig configures exponential backoff with jitter.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+
+	// BaseDelay bounds the delay before the first retry, which is drawn at
+	// random from [0, BaseDelay).
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff so it never waits longer than this.
+	MaxDelay time.Duration
+}
+
+// defaults fills in sane values for any field left at its zero value.
+func (cfg RetryConfig) defaults() RetryConfig {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 50 * time.Millisecond
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 2 * time.Second
+	}
+
+	return cfg
+}
+
+// backoff returns how long to wait before the retry following attempt
+// (0-based), as an exponentially growing delay with full jitter.
+func (cfg RetryConfig) backoff(attempt int) time.Duration {
+	delay := cfg.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > cfg.MaxDelay {
+		delay = cfg.MaxDelay
+	}
+
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+// =============================================================================
+
+// retryPuller wraps a Puller, retrying Pull while it returns a temporary
+// error.
+type retryPuller struct {
+	inner etl.Puller
+	cfg   RetryConfig
+}
+
+// RetryPuller decorates inner so a Pull that fails with a temporary error is
+// retried, with exponential backoff and jitter, for up to cfg.MaxAttempts
+// attempts in all.
+func RetryPuller(inner etl.Puller, cfg RetryConfig) etl.Puller {
+	return &retryPuller{inner: inner, cfg: cfg.defaults()}
+}
+
+// Pull calls through to the wrapped Puller, retrying on a temporary error.
+func (p *retryPuller) Pull(d *etl.Data) error {
+	var err error
+	for attempt := 0; attempt < p.cfg.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(p.cfg.backoff(attempt - 1))
+		}
+
+		if err = p.inner.Pull(d); !etl.IsTemporary(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// =============================================================================
+
+// retryStorer wraps a Storer, retrying Store while it returns a temporary
+// error.
+type retryStorer struct {
+	inner etl.Storer
+	cfg   RetryConfig
+}
+
+// RetryStorer decorates inner so a Store that fails with a temporary error
+// is retried, with exponential backoff and jitter, for up to
+// cfg.MaxAttempts attempts in all.
+func RetryStorer(inner etl.Storer, cfg RetryConfig) etl.Storer {
+	return &retryStorer{inner: inner, cfg: cfg.defaults()}
+}
+
+// Store calls through to the wrapped Storer, retrying on a temporary error.
+func (s *retryStorer) Store(d etl.Data) error {
+	var err error
+	for attempt := 0; attempt < s.cfg.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(s.cfg.backoff(attempt - 1))
+		}
+
+		if err = s.inner.Store(d); !etl.IsTemporary(err) {
+			return err
+		}
+	}
+
+	return err
+}